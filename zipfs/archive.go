@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArchiveEntry describes a single member of an archive, independent of the
+// underlying format.
+type ArchiveEntry struct {
+	Name    string
+	Mode    os.FileMode
+	Size    int64
+	ModTime time.Time
+	// LinkTarget is the symlink target for entries with the ModeSymlink
+	// bit set, when the format stores it out of band (tar headers carry
+	// it directly). It is empty for formats that store the target as the
+	// entry's body instead (zip), which callers read with Open.
+	LinkTarget string
+}
+
+// ArchiveFS is the interface implemented by each supported archive format.
+// It exposes just enough to build the FUSE tree: the full entry list (so
+// Lookup/ReadDirAll don't need format-specific traversal) and a way to open
+// a given entry for sequential reading.
+type ArchiveFS interface {
+	// Entries returns every entry in the archive, in archive order.
+	Entries() []ArchiveEntry
+	// Open returns a reader for the named entry's contents. The name must
+	// match Name from one of Entries exactly.
+	Open(name string) (io.ReadCloser, error)
+	// Close releases any resources (open files, etc.) held by the archive.
+	Close() error
+}
+
+// EntryHandle is a handle to an open archive entry that supports reads at
+// arbitrary offsets, for mmap/pread-style access.
+type EntryHandle interface {
+	ReadAt(p []byte, off int64) (int, error)
+	Close() error
+}
+
+// RandomAccessArchive is implemented by archive backends that can produce
+// an EntryHandle optimized for random-access reads (see zipArchive).
+// Backends without a native implementation are served by
+// genericEntryHandle, which repositions a sequential reader under the
+// hood by discarding forward or reopening on a backward seek.
+type RandomAccessArchive interface {
+	OpenHandle(name string) (EntryHandle, error)
+}
+
+// openEntryHandle returns an EntryHandle for name, using archive's native
+// implementation when available.
+func openEntryHandle(archive ArchiveFS, name string) (EntryHandle, error) {
+	if ra, ok := archive.(RandomAccessArchive); ok {
+		return ra.OpenHandle(name)
+	}
+	return &genericEntryHandle{archive: archive, name: name}, nil
+}
+
+// genericEntryHandle adapts ArchiveFS.Open, which only reads sequentially
+// from the start of an entry, into an EntryHandle. A forward seek is
+// served by discarding bytes; a backward seek reopens the entry and
+// discards from the start. bazil.org/fuse dispatches each request on its
+// own goroutine, so ReadAt can be called concurrently on the same handle
+// (readahead, concurrent pread from multiple threads); mu serializes
+// access to the reposition state so concurrent calls can't interleave.
+type genericEntryHandle struct {
+	archive ArchiveFS
+	name    string
+
+	mu  sync.Mutex
+	r   io.ReadCloser
+	pos int64
+}
+
+var _ EntryHandle = (*genericEntryHandle)(nil)
+
+func (h *genericEntryHandle) ReadAt(p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.r == nil || off < h.pos {
+		if h.r != nil {
+			h.r.Close()
+		}
+		r, err := h.archive.Open(h.name)
+		if err != nil {
+			return 0, err
+		}
+		h.r = r
+		h.pos = 0
+	}
+	if off > h.pos {
+		if _, err := io.CopyN(io.Discard, h.r, off-h.pos); err != nil {
+			return 0, err
+		}
+		h.pos = off
+	}
+	n, err := h.r.Read(p)
+	h.pos += int64(n)
+	return n, err
+}
+
+func (h *genericEntryHandle) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.r == nil {
+		return nil
+	}
+	return h.r.Close()
+}
+
+// OpenArchive opens path and returns the ArchiveFS implementation matching
+// its format, detected from magic bytes and falling back to the file
+// extension. path may also be an http:// or https:// URL, in which case
+// it is mounted as a remote zip archive read with ranged GETs (see
+// openRemoteZip); cacheSize controls that remote block cache's size in
+// bytes and is ignored for local paths.
+func OpenArchive(path string, cacheSize int) (ArchiveFS, error) {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return openRemoteZip(path, cacheSize)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var header [262]byte
+	n, err := io.ReadFull(f, header[:])
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	switch {
+	case n >= 4 && bytes.HasPrefix(header[:4], []byte("PK\x03\x04")),
+		n >= 4 && bytes.HasPrefix(header[:4], []byte("PK\x05\x06")):
+		return openZipFile(f)
+	case n >= 2 && header[0] == 0x1f && header[1] == 0x8b:
+		return openTar(f, path, tarGzip)
+	case n >= 3 && string(header[:3]) == "BZh":
+		return openTar(f, path, tarBzip2)
+	case n >= 262 && string(header[257:262]) == "ustar":
+		return openTar(f, path, tarPlain)
+	default:
+		// Fall back to extension sniffing for truncated or otherwise
+		// ambiguous headers (e.g. empty tar archives).
+		f.Close()
+		return openArchiveByExtension(path)
+	}
+}
+
+func openArchiveByExtension(path string) (ArchiveFS, error) {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return openZipFile(f)
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return openTar(f, path, tarGzip)
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return openTar(f, path, tarBzip2)
+	case strings.HasSuffix(lower, ".tar"):
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return openTar(f, path, tarPlain)
+	default:
+		return nil, fmt.Errorf("%s: unrecognized archive format", path)
+	}
+}
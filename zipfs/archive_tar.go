@@ -0,0 +1,179 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// tarCompression identifies the outer compression layer wrapping a tar
+// stream, if any.
+type tarCompression int
+
+const (
+	tarPlain tarCompression = iota
+	tarGzip
+	tarBzip2
+)
+
+// tarArchive is an ArchiveFS backed by a tar stream. Tar is streaming-only,
+// so unlike zip there is no central directory: on open we do one pass over
+// the (decompressed) stream to build an index of name -> header plus the
+// entry's starting byte offset within the decompressed stream. Reads
+// reopen the archive and discard up to that offset, mirroring how the
+// zipfs deflate path skip-reads to a seek target.
+type tarArchive struct {
+	path        string
+	compression tarCompression
+	entries     []ArchiveEntry
+	index       map[string]tarIndexEntry
+}
+
+// tarIndexEntry records where an entry's content starts in the
+// decompressed stream, and how long it runs.
+type tarIndexEntry struct {
+	offset int64
+	size   int64
+}
+
+var _ ArchiveFS = (*tarArchive)(nil)
+
+// countingReader tracks how many bytes have been read through it, so we
+// can record the decompressed-stream offset at which each tar entry's
+// content begins.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func decompressStream(f *os.File, compression tarCompression) (io.Reader, error) {
+	switch compression {
+	case tarGzip:
+		return gzip.NewReader(f)
+	case tarBzip2:
+		return bzip2.NewReader(f), nil
+	default:
+		return f, nil
+	}
+}
+
+func openTar(f *os.File, path string, compression tarCompression) (ArchiveFS, error) {
+	defer f.Close()
+
+	stream, err := decompressStream(f, compression)
+	if err != nil {
+		return nil, err
+	}
+	cr := &countingReader{r: stream}
+	tr := tar.NewReader(cr)
+
+	ta := &tarArchive{
+		path:        path,
+		compression: compression,
+		index:       make(map[string]tarIndexEntry),
+	}
+
+	// Hardlink entries carry no body of their own (Size is 0 and
+	// FileInfo().Mode() is indistinguishable from a regular file); the
+	// actual content lives under Linkname, which tar writers emit before
+	// the hardlink pointing to it. Record them here and resolve once the
+	// full index is built, rather than assuming the target always
+	// precedes the link in this pass.
+	type pendingHardlink struct {
+		entryIdx int
+		target   string
+	}
+	var hardlinks []pendingHardlink
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		entry := ArchiveEntry{
+			Name:       hdr.Name,
+			Mode:       hdr.FileInfo().Mode(),
+			Size:       hdr.Size,
+			ModTime:    hdr.ModTime,
+			LinkTarget: hdr.Linkname,
+		}
+		if hdr.Typeflag == tar.TypeLink {
+			hardlinks = append(hardlinks, pendingHardlink{entryIdx: len(ta.entries), target: hdr.Linkname})
+		} else {
+			ta.index[hdr.Name] = tarIndexEntry{offset: cr.n, size: hdr.Size}
+		}
+		ta.entries = append(ta.entries, entry)
+	}
+
+	for _, hl := range hardlinks {
+		target, ok := ta.index[hl.target]
+		if !ok {
+			// Dangling hardlink (target missing or itself unresolved): it
+			// was never given an index entry in the first pass, so it
+			// stays out of the index here too. It still appears in
+			// Entries, but Open returns os.ErrNotExist for it, same as
+			// any other archive member with no recoverable content.
+			continue
+		}
+		ta.index[ta.entries[hl.entryIdx].Name] = target
+		ta.entries[hl.entryIdx].Size = target.size
+	}
+
+	return ta, nil
+}
+
+func (t *tarArchive) Entries() []ArchiveEntry {
+	return t.entries
+}
+
+func (t *tarArchive) Open(name string) (io.ReadCloser, error) {
+	idx, ok := t.index[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	f, err := os.Open(t.path)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := decompressStream(f, t.compression)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, stream, idx.offset); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &tarEntryReader{f: f, r: io.LimitReader(stream, idx.size)}, nil
+}
+
+func (t *tarArchive) Close() error {
+	return nil
+}
+
+// tarEntryReader wraps the limited entry reader together with the
+// underlying file so Close releases the file descriptor opened in Open.
+type tarEntryReader struct {
+	f *os.File
+	r io.Reader
+}
+
+func (r *tarEntryReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func (r *tarEntryReader) Close() error {
+	return r.f.Close()
+}
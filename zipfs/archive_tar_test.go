@@ -0,0 +1,123 @@
+package main
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestOpenArchiveTarHardlink exercises a tar hardlink entry, which stores no
+// body of its own (Size 0, Linkname pointing at the real entry) and so must
+// resolve to its target's content and size rather than mounting as an empty
+// regular file.
+func TestOpenArchiveTarHardlink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.tar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const body = "hello, world\n"
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Name: "hello.txt", Mode: 0644, Size: int64(len(body))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(tw, body); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: "hello-link.txt", Typeflag: tar.TypeLink, Linkname: "hello.txt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := OpenArchive(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	entries := archive.Entries()
+	var link *ArchiveEntry
+	for i := range entries {
+		if entries[i].Name == "hello-link.txt" {
+			link = &entries[i]
+		}
+	}
+	if link == nil {
+		t.Fatal("hello-link.txt not found in entries")
+	}
+	if link.Size != int64(len(body)) {
+		t.Fatalf("hello-link.txt Size = %d, want %d (the hardlink target's size)", link.Size, len(body))
+	}
+
+	r, err := archive.Open("hello-link.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body {
+		t.Fatalf("Open(hello-link.txt) = %q, want %q", got, body)
+	}
+}
+
+// TestOpenArchiveTarDeviceMode checks that tar device/fifo entries surface
+// the corresponding os.FileMode bits, which is all fuse.Attr.Mode needs to
+// represent them correctly: archive/tar's Header.FileInfo().Mode() already
+// sets these, so there is nothing format-specific for this package to add,
+// but this pins that behavior against regression.
+func TestOpenArchiveTarDeviceMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.tar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	headers := []*tar.Header{
+		{Name: "fifo", Typeflag: tar.TypeFifo, Mode: 0644},
+		{Name: "chardev", Typeflag: tar.TypeChar, Mode: 0644, Devmajor: 1, Devminor: 3},
+		{Name: "blockdev", Typeflag: tar.TypeBlock, Mode: 0644, Devmajor: 8, Devminor: 0},
+	}
+	for _, hdr := range headers {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := OpenArchive(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	want := map[string]os.FileMode{
+		"fifo":     os.ModeNamedPipe,
+		"chardev":  os.ModeDevice | os.ModeCharDevice,
+		"blockdev": os.ModeDevice,
+	}
+	for _, e := range archive.Entries() {
+		w, ok := want[e.Name]
+		if !ok {
+			continue
+		}
+		if e.Mode&(os.ModeNamedPipe|os.ModeDevice|os.ModeCharDevice) != w {
+			t.Errorf("%s Mode = %v, want mode bits %v", e.Name, e.Mode, w)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type fixtureFile struct {
+	name string
+	body string
+}
+
+var fixtureFiles = []fixtureFile{
+	{"hello.txt", "hello, world\n"},
+	{"dir/nested.txt", "nested contents\n"},
+}
+
+func writeZipFixture(t *testing.T, path string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, ff := range fixtureFiles {
+		w, err := zw.Create(ff.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(w, ff.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarFixture(t *testing.T, path string, gzipped bool) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if gzipped {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	for _, ff := range fixtureFiles {
+		hdr := &tar.Header{
+			Name: ff.name,
+			Mode: 0644,
+			Size: int64(len(ff.body)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, ff.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func checkArchive(t *testing.T, archive ArchiveFS) {
+	t.Helper()
+
+	entries := archive.Entries()
+	if len(entries) != len(fixtureFiles) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(fixtureFiles))
+	}
+
+	for _, ff := range fixtureFiles {
+		r, err := archive.Open(ff.name)
+		if err != nil {
+			t.Errorf("Open(%q): %v", ff.name, err)
+			continue
+		}
+		got, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Errorf("ReadAll(%q): %v", ff.name, err)
+			continue
+		}
+		if !bytes.Equal(got, []byte(ff.body)) {
+			t.Errorf("Open(%q) = %q, want %q", ff.name, got, ff.body)
+		}
+	}
+}
+
+func TestOpenArchiveZip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.zip")
+	writeZipFixture(t, path)
+
+	archive, err := OpenArchive(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	checkArchive(t, archive)
+}
+
+func TestOpenArchiveTar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.tar")
+	writeTarFixture(t, path, false)
+
+	archive, err := OpenArchive(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	checkArchive(t, archive)
+}
+
+func TestOpenArchiveTarGz(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.tar.gz")
+	writeTarFixture(t, path, true)
+
+	archive, err := OpenArchive(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	checkArchive(t, archive)
+}
+
+// TestOpenArchiveTarBz2 exercises the bzip2 path against a precomputed
+// fixture in testdata: compress/bzip2 in the standard library only
+// implements a reader, so unlike the other formats this fixture can't be
+// generated on the fly and was produced once with the system bzip2 from
+// the same hello.txt/dir/nested.txt contents as fixtureFiles.
+func TestOpenArchiveTarBz2(t *testing.T) {
+	archive, err := OpenArchive(filepath.Join("testdata", "fixture.tar.bz2"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	checkArchive(t, archive)
+}
+
+// TestGenericEntryHandleConcurrentReads exercises genericEntryHandle (the
+// fallback used for tar entries) from many goroutines against a single
+// shared handle, as bazil.org/fuse does when it dispatches concurrent
+// requests against one open file.
+func TestGenericEntryHandleConcurrentReads(t *testing.T) {
+	body := make([]byte, 64*1024)
+	rand.New(rand.NewSource(3)).Read(body)
+
+	path := filepath.Join(t.TempDir(), "fixture.tar")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{Name: "data.bin", Mode: 0644, Size: int64(len(body))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := OpenArchive(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	h, err := openEntryHandle(archive, "data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	offsets := []int64{0, 40000, 50, 20000, 60000, 10}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		off := offsets[i%len(offsets)]
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			want := body[off : off+1024]
+			got := make([]byte, 1024)
+			n, err := h.ReadAt(got, off)
+			if err != nil && err != io.EOF {
+				t.Errorf("ReadAt(off=%d): %v", off, err)
+				return
+			}
+			if n != len(got) {
+				t.Errorf("ReadAt(off=%d) returned %d bytes, want %d", off, n, len(got))
+				return
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("ReadAt(off=%d) mismatch", off)
+			}
+		}(off)
+	}
+	wg.Wait()
+}
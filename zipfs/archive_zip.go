@@ -0,0 +1,276 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"sync"
+)
+
+// zipArchive adapts *zip.Reader to ArchiveFS, keeping the backing file open
+// for the lifetime of the archive.
+type zipArchive struct {
+	ra      io.ReaderAt
+	closer  io.Closer
+	entries []ArchiveEntry
+	byName  map[string]*zip.File
+}
+
+var _ ArchiveFS = (*zipArchive)(nil)
+var _ RandomAccessArchive = (*zipArchive)(nil)
+
+func openZipFile(f *os.File) (ArchiveFS, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return openZip(f, fi.Size(), f)
+}
+
+// openRemoteZip mounts a zip archive served over HTTP(S), reading it with
+// ranged GETs through an LRU block cache instead of downloading it.
+func openRemoteZip(url string, cacheSize int) (ArchiveFS, error) {
+	hr, err := newHTTPRangeReaderAt(url)
+	if err != nil {
+		return nil, err
+	}
+	cache := newBlockCache(hr, defaultBlockSize, cacheSize)
+	return openZip(cache, cache.Size(), nil)
+}
+
+// openZip builds a zipArchive from a reader and its size. closer may be
+// nil when the source (e.g. an HTTP reader) has nothing to release.
+func openZip(ra io.ReaderAt, size int64, closer io.Closer) (ArchiveFS, error) {
+	r, err := zip.NewReader(ra, size)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, err
+	}
+
+	za := &zipArchive{
+		ra:     ra,
+		closer: closer,
+		byName: make(map[string]*zip.File, len(r.File)),
+	}
+	for _, zf := range r.File {
+		za.byName[zf.Name] = zf
+		za.entries = append(za.entries, ArchiveEntry{
+			Name:    zf.Name,
+			Mode:    zf.Mode(),
+			Size:    int64(zf.UncompressedSize64),
+			ModTime: zf.ModTime(),
+		})
+	}
+	return za, nil
+}
+
+func (z *zipArchive) Entries() []ArchiveEntry {
+	return z.entries
+}
+
+func (z *zipArchive) Open(name string) (io.ReadCloser, error) {
+	zf, ok := z.byName[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return zf.Open()
+}
+
+func (z *zipArchive) Close() error {
+	if z.closer == nil {
+		return nil
+	}
+	return z.closer.Close()
+}
+
+// OpenHandle returns a handle optimized for random-access reads. Stored
+// (uncompressed) entries are read directly from the archive's underlying
+// io.ReaderAt at their data offset, with no decompression involved.
+// Deflated entries go through a chunked handle that caches recently
+// decompressed chunks and only reopens the entry's reader when a seek
+// can't be served by reading forward from the current position.
+func (z *zipArchive) OpenHandle(name string) (EntryHandle, error) {
+	zf, ok := z.byName[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	if zf.Method == zip.Store {
+		dataOffset, err := zf.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		return &storeHandle{
+			ra:     z.ra,
+			offset: dataOffset,
+			size:   int64(zf.UncompressedSize64),
+		}, nil
+	}
+
+	return &deflateHandle{
+		zf:    zf,
+		cache: make(map[int64]*deflateChunk, deflateChunksPerHandle),
+	}, nil
+}
+
+// storeHandle serves reads for a Store (uncompressed) zip entry directly
+// from the archive's backing reader, with no decompression or buffering.
+type storeHandle struct {
+	ra     io.ReaderAt
+	offset int64
+	size   int64
+}
+
+var _ EntryHandle = (*storeHandle)(nil)
+
+func (h *storeHandle) ReadAt(p []byte, off int64) (int, error) {
+	if off >= h.size {
+		return 0, io.EOF
+	}
+	if off+int64(len(p)) > h.size {
+		p = p[:h.size-off]
+	}
+	return h.ra.ReadAt(p, h.offset+off)
+}
+
+func (h *storeHandle) Close() error {
+	return nil
+}
+
+const (
+	deflateChunkSize       = 128 * 1024
+	deflateChunksPerHandle = 8
+)
+
+type deflateChunk struct {
+	data []byte
+}
+
+// deflateHandle serves random-access reads for a deflated zip entry. It
+// keeps a bounded LRU of decompressed chunks and a single live decompressor
+// positioned at streamPos; a read that isn't satisfied by the cache either
+// continues reading forward from streamPos (when the target chunk is at or
+// just ahead of it) or reopens the entry and skip-reads to the target
+// chunk boundary (on a backward seek, or a forward seek of more than one
+// chunk). bazil.org/fuse dispatches each request on its own goroutine, so
+// ReadAt can be called concurrently on the same handle; mu serializes
+// access to the decompressor and cache so concurrent calls can't
+// interleave reads or corrupt the LRU.
+type deflateHandle struct {
+	zf *zip.File
+
+	mu        sync.Mutex
+	r         io.ReadCloser
+	streamPos int64
+
+	cache    map[int64]*deflateChunk
+	lruOrder []int64 // most-recently-used at the end
+}
+
+var _ EntryHandle = (*deflateHandle)(nil)
+
+func (h *deflateHandle) ReadAt(p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	size := int64(h.zf.UncompressedSize64)
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	total := 0
+	for total < len(p) && off < size {
+		chunkIdx := off / deflateChunkSize
+		chunk, err := h.chunk(chunkIdx)
+		if err != nil {
+			return total, err
+		}
+		chunkStart := chunkIdx * deflateChunkSize
+		n := copy(p[total:], chunk.data[off-chunkStart:])
+		total += n
+		off += int64(n)
+	}
+	if total == 0 {
+		return 0, io.EOF
+	}
+	return total, nil
+}
+
+func (h *deflateHandle) chunk(idx int64) (*deflateChunk, error) {
+	if c, ok := h.cache[idx]; ok {
+		h.touch(idx)
+		return c, nil
+	}
+
+	chunkStart := idx * deflateChunkSize
+	switch {
+	case h.r == nil, chunkStart < h.streamPos, chunkStart > h.streamPos+deflateChunkSize:
+		if h.r != nil {
+			h.r.Close()
+		}
+		r, err := h.zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.CopyN(io.Discard, r, chunkStart); err != nil && err != io.EOF {
+			r.Close()
+			return nil, err
+		}
+		h.r = r
+		h.streamPos = chunkStart
+	case chunkStart > h.streamPos:
+		if _, err := io.CopyN(io.Discard, h.r, chunkStart-h.streamPos); err != nil && err != io.EOF {
+			return nil, err
+		}
+		h.streamPos = chunkStart
+	}
+
+	size := int64(h.zf.UncompressedSize64)
+	want := int64(deflateChunkSize)
+	if chunkStart+want > size {
+		want = size - chunkStart
+	}
+	buf := make([]byte, want)
+	n, err := io.ReadFull(h.r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	h.streamPos += int64(n)
+
+	c := &deflateChunk{data: buf[:n]}
+	h.cache[idx] = c
+	h.touch(idx)
+	h.evictIfNeeded()
+	return c, nil
+}
+
+func (h *deflateHandle) touch(idx int64) {
+	for i, v := range h.lruOrder {
+		if v == idx {
+			h.lruOrder = append(h.lruOrder[:i], h.lruOrder[i+1:]...)
+			break
+		}
+	}
+	h.lruOrder = append(h.lruOrder, idx)
+}
+
+func (h *deflateHandle) evictIfNeeded() {
+	for len(h.lruOrder) > deflateChunksPerHandle {
+		oldest := h.lruOrder[0]
+		h.lruOrder = h.lruOrder[1:]
+		delete(h.cache, oldest)
+	}
+}
+
+func (h *deflateHandle) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.r == nil {
+		return nil
+	}
+	return h.r.Close()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// writeZipFixtureWithMethod writes a single entry using the given
+// compression method (zip.Store or zip.Deflate) so random-access reads can
+// be exercised against both code paths.
+func writeZipFixtureWithMethod(t *testing.T, path, name string, body []byte, method uint16) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testRandomAccessReads(t *testing.T, method uint16) {
+	t.Helper()
+
+	body := make([]byte, 400*1024) // multiple deflate chunks worth
+	rand.New(rand.NewSource(1)).Read(body)
+
+	path := filepath.Join(t.TempDir(), "fixture.zip")
+	writeZipFixtureWithMethod(t, path, "data.bin", body, method)
+
+	archive, err := OpenArchive(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	h, err := openEntryHandle(archive, "data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	offsets := []int64{0, 300000, 50, 200*1024 + 7, 399000, 10}
+	for _, off := range offsets {
+		want := body[off : off+1024]
+		got := make([]byte, 1024)
+		n, err := h.ReadAt(got, off)
+		if err != nil && err != io.EOF {
+			t.Fatalf("ReadAt(off=%d): %v", off, err)
+		}
+		if n != len(got) {
+			t.Fatalf("ReadAt(off=%d) returned %d bytes, want %d", off, n, len(got))
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("ReadAt(off=%d) mismatch", off)
+		}
+	}
+}
+
+func TestRandomAccessReadsStore(t *testing.T) {
+	testRandomAccessReads(t, zip.Store)
+}
+
+func TestRandomAccessReadsDeflate(t *testing.T) {
+	testRandomAccessReads(t, zip.Deflate)
+}
+
+// testConcurrentReads exercises ReadAt from many goroutines against a
+// single shared handle, as bazil.org/fuse does when it dispatches
+// concurrent requests against one open file. Without the handle's mutex
+// this reliably corrupts data under -race and often even without it.
+func testConcurrentReads(t *testing.T, method uint16) {
+	t.Helper()
+
+	body := make([]byte, 400*1024) // multiple deflate chunks worth
+	rand.New(rand.NewSource(2)).Read(body)
+
+	path := filepath.Join(t.TempDir(), "fixture.zip")
+	writeZipFixtureWithMethod(t, path, "data.bin", body, method)
+
+	archive, err := OpenArchive(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	h, err := openEntryHandle(archive, "data.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	offsets := []int64{0, 300000, 50, 200*1024 + 7, 399000, 10, 128*1024 - 5, 256 * 1024}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		off := offsets[i%len(offsets)]
+		wg.Add(1)
+		go func(off int64) {
+			defer wg.Done()
+			want := body[off : off+1024]
+			got := make([]byte, 1024)
+			n, err := h.ReadAt(got, off)
+			if err != nil && err != io.EOF {
+				t.Errorf("ReadAt(off=%d): %v", off, err)
+				return
+			}
+			if n != len(got) {
+				t.Errorf("ReadAt(off=%d) returned %d bytes, want %d", off, n, len(got))
+				return
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("ReadAt(off=%d) mismatch", off)
+			}
+		}(off)
+	}
+	wg.Wait()
+}
+
+func TestConcurrentReadsStore(t *testing.T) {
+	testConcurrentReads(t, zip.Store)
+}
+
+func TestConcurrentReadsDeflate(t *testing.T) {
+	testConcurrentReads(t, zip.Deflate)
+}
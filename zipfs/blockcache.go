@@ -0,0 +1,137 @@
+package main
+
+import (
+	"container/list"
+	"io"
+	"sync"
+)
+
+const (
+	defaultBlockSize = 64 * 1024
+	defaultCacheSize = 16 * 1024 * 1024
+)
+
+// sizedReaderAt is an io.ReaderAt with a known, fixed size.
+type sizedReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// blockCache wraps a sizedReaderAt with an LRU cache of fixed-size blocks.
+// It exists to keep repeated small reads against the same region (as FUSE
+// directory scans and the zip central-directory parse both do) from
+// re-issuing a round trip per read.
+type blockCache struct {
+	under     sizedReaderAt
+	blockSize int
+	maxBlocks int
+
+	mu    sync.Mutex
+	ll    *list.List // most-recently-used at the front
+	elems map[int64]*list.Element
+}
+
+type cacheBlock struct {
+	index int64
+	data  []byte
+}
+
+// newBlockCache wraps under in an LRU cache holding up to cacheSize bytes,
+// in blockSize chunks. A cacheSize or blockSize <= 0 selects the package
+// defaults.
+func newBlockCache(under sizedReaderAt, blockSize, cacheSize int) *blockCache {
+	if blockSize <= 0 {
+		blockSize = defaultBlockSize
+	}
+	if cacheSize <= 0 {
+		cacheSize = defaultCacheSize
+	}
+	maxBlocks := cacheSize / blockSize
+	if maxBlocks < 1 {
+		maxBlocks = 1
+	}
+	return &blockCache{
+		under:     under,
+		blockSize: blockSize,
+		maxBlocks: maxBlocks,
+		ll:        list.New(),
+		elems:     make(map[int64]*list.Element),
+	}
+}
+
+func (c *blockCache) Size() int64 {
+	return c.under.Size()
+}
+
+func (c *blockCache) Read(index int64) (*cacheBlock, error) {
+	c.mu.Lock()
+	if elem, ok := c.elems[index]; ok {
+		c.ll.MoveToFront(elem)
+		block := elem.Value.(*cacheBlock)
+		c.mu.Unlock()
+		return block, nil
+	}
+	c.mu.Unlock()
+
+	start := index * int64(c.blockSize)
+	size := c.under.Size()
+	end := start + int64(c.blockSize)
+	if end > size {
+		end = size
+	}
+	if start >= end {
+		return &cacheBlock{index: index}, nil
+	}
+
+	buf := make([]byte, end-start)
+	if _, err := c.under.ReadAt(buf, start); err != nil {
+		return nil, err
+	}
+	block := &cacheBlock{index: index, data: buf}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.elems[index]; ok {
+		// lost a race with a concurrent fetch of the same block
+		c.ll.MoveToFront(elem)
+		return elem.Value.(*cacheBlock), nil
+	}
+	elem := c.ll.PushFront(block)
+	c.elems[index] = elem
+	for c.ll.Len() > c.maxBlocks {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.elems, oldest.Value.(*cacheBlock).index)
+	}
+	return block, nil
+}
+
+var _ io.ReaderAt = (*blockCache)(nil)
+
+func (c *blockCache) ReadAt(p []byte, off int64) (int, error) {
+	if off >= c.under.Size() {
+		return 0, io.EOF
+	}
+	total := 0
+	for len(p) > 0 {
+		index := off / int64(c.blockSize)
+		blockOff := off % int64(c.blockSize)
+
+		block, err := c.Read(index)
+		if err != nil {
+			return total, err
+		}
+		if blockOff >= int64(len(block.data)) {
+			return total, io.EOF
+		}
+
+		n := copy(p, block.data[blockOff:])
+		total += n
+		off += int64(n)
+		p = p[n:]
+	}
+	return total, nil
+}
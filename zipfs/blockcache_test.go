@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+type fakeReaderAt struct {
+	data  []byte
+	reads int
+}
+
+func (f *fakeReaderAt) Size() int64 { return int64(len(f.data)) }
+
+func (f *fakeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	f.reads++
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
+
+func TestBlockCacheReadAt(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000) // 100000 bytes
+	under := &fakeReaderAt{data: data}
+	cache := newBlockCache(under, 4096, 3*4096)
+
+	buf := make([]byte, 100)
+	for _, off := range []int64{0, 4096, 8192 + 50, int64(len(data) - 100)} {
+		if _, err := cache.ReadAt(buf, off); err != nil {
+			t.Fatalf("ReadAt(off=%d): %v", off, err)
+		}
+		if !bytes.Equal(buf, data[off:off+100]) {
+			t.Fatalf("ReadAt(off=%d) mismatch", off)
+		}
+	}
+}
+
+func TestBlockCacheHitsDontRefetch(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 4096*4)
+	under := &fakeReaderAt{data: data}
+	cache := newBlockCache(under, 4096, 4096*4)
+
+	buf := make([]byte, 10)
+	for i := 0; i < 5; i++ {
+		if _, err := cache.ReadAt(buf, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if under.reads != 1 {
+		t.Fatalf("expected 1 underlying read for repeated hits, got %d", under.reads)
+	}
+}
+
+func TestBlockCacheEvictsLRU(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 4096*4)
+	under := &fakeReaderAt{data: data}
+	// only room for 2 blocks
+	cache := newBlockCache(under, 4096, 4096*2)
+
+	buf := make([]byte, 10)
+	cache.ReadAt(buf, 0)      // block 0
+	cache.ReadAt(buf, 4096)   // block 1
+	cache.ReadAt(buf, 4096*2) // block 2, evicts block 0
+	under.reads = 0
+	cache.ReadAt(buf, 0) // block 0 must be re-fetched
+	if under.reads != 1 {
+		t.Fatalf("expected evicted block to be re-fetched, got %d underlying reads", under.reads)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// httpRangeReaderAt is an io.ReaderAt over a remote file, implemented with
+// HTTP Range requests. It lets zip.NewReader operate on an archive that
+// lives in object storage without downloading it first.
+type httpRangeReaderAt struct {
+	url    string
+	client *http.Client
+	size   int64
+}
+
+// newHTTPRangeReaderAt probes url to determine its size and range support.
+// It first tries a HEAD request looking for Accept-Ranges and
+// Content-Length. Some servers don't answer HEAD usefully (or at all), so
+// if that doesn't pan out it falls back to a ranged GET for the last
+// 64 KiB, which is enough to find the central directory of most zips and
+// also reports the full size via Content-Range.
+func newHTTPRangeReaderAt(url string) (*httpRangeReaderAt, error) {
+	client := http.DefaultClient
+	r := &httpRangeReaderAt{url: url, client: client}
+
+	if size, ok := r.probeHead(); ok {
+		r.size = size
+		return r, nil
+	}
+
+	const tailSize = 64 * 1024
+	buf := make([]byte, tailSize)
+	n, size, err := r.getRange(-tailSize, buf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("%s: server does not support range requests", url)
+	}
+	r.size = size
+	_ = n
+	return r, nil
+}
+
+func (r *httpRangeReaderAt) probeHead() (size int64, ok bool) {
+	req, err := http.NewRequest(http.MethodHead, r.url, nil)
+	if err != nil {
+		return 0, false
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+	if !strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes") {
+		return 0, false
+	}
+	if resp.ContentLength <= 0 {
+		return 0, false
+	}
+	return resp.ContentLength, true
+}
+
+// getRange issues a GET with a Range header. A negative off requests the
+// trailing len(p) bytes of the resource (an HTTP "suffix range"). It
+// returns the number of bytes read into p and, when the server reports it
+// via Content-Range, the full resource size.
+func (r *httpRangeReaderAt) getRange(off int64, p []byte) (n int, size int64, err error) {
+	var rangeHeader string
+	if off < 0 {
+		rangeHeader = fmt.Sprintf("bytes=%d", off)
+	} else {
+		rangeHeader = fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Range", rangeHeader)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		size = parseContentRangeSize(resp.Header.Get("Content-Range"))
+	case http.StatusOK:
+		// The server ignored the Range header and sent the whole body from
+		// the start, not the requested range: trusting ContentLength here
+		// would make the caller believe p holds bytes from off when it
+		// actually holds bytes from 0. Report this as a clear error instead
+		// of silently handing back misaligned data.
+		return 0, 0, fmt.Errorf("range request: server does not support range requests (ignored Range: %s)", rangeHeader)
+	default:
+		return 0, 0, fmt.Errorf("range request: unexpected status %s", resp.Status)
+	}
+
+	n, err = io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	return n, size, err
+}
+
+// parseContentRangeSize extracts the total resource size from a
+// "Content-Range: bytes start-end/size" header.
+func parseContentRangeSize(h string) int64 {
+	i := strings.LastIndexByte(h, '/')
+	if i < 0 || i+1 >= len(h) {
+		return 0
+	}
+	size, err := strconv.ParseInt(h[i+1:], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+func (r *httpRangeReaderAt) Size() int64 {
+	return r.size
+}
+
+func (r *httpRangeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	if off+int64(len(p)) > r.size {
+		p = p[:r.size-off]
+	}
+	n, _, err := r.getRange(off, p)
+	if err != nil {
+		return n, err
+	}
+	if n < len(p) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
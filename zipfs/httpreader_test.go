@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPRangeReaderAtNoRangeSupport(t *testing.T) {
+	// A server that ignores Range and always returns the whole body with
+	// 200 OK, as some static file hosts do.
+	body := strings.Repeat("x", 128*1024)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	_, err := newHTTPRangeReaderAt(srv.URL)
+	if err == nil {
+		t.Fatal("expected an error for a server that doesn't support range requests")
+	}
+	if !strings.Contains(err.Error(), "does not support range requests") {
+		t.Fatalf("err = %v, want a clear range-support error", err)
+	}
+}
+
+func TestHTTPRangeReaderAtReadAt(t *testing.T) {
+	body := strings.Repeat("0123456789", 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.ServeContent(w, req, "fixture", time.Time{}, strings.NewReader(body))
+	}))
+	defer srv.Close()
+
+	r, err := newHTTPRangeReaderAt(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Size() != int64(len(body)) {
+		t.Fatalf("Size() = %d, want %d", r.Size(), len(body))
+	}
+
+	buf := make([]byte, 10)
+	if _, err := r.ReadAt(buf, 20); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != body[20:30] {
+		t.Fatalf("ReadAt(20) = %q, want %q", buf, body[20:30])
+	}
+}
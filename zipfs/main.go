@@ -1,7 +1,6 @@
 package main
 
 import (
-	"archive/zip"
 	"context"
 	"flag"
 	"fmt"
@@ -9,7 +8,6 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"bazil.org/fuse"
 	"bazil.org/fuse/fs"
@@ -19,100 +17,99 @@ var progName = filepath.Base(os.Args[0])
 
 func usage() {
 	fmt.Fprintf(os.Stderr, "Usage of %s:\n", progName)
-	fmt.Fprintf(os.Stderr, "  %s file.zip mountpoint\n", progName)
+	fmt.Fprintf(os.Stderr, "  %s archive mountpoint\n", progName)
+	fmt.Fprintf(os.Stderr, "  %s -multi mountpoint\n", progName)
+	fmt.Fprintf(os.Stderr, "archive is a .zip, .tar, .tar.gz, or .tar.bz2 file,\n")
+	fmt.Fprintf(os.Stderr, "or an http:// or https:// URL to a zip file\n")
+	fmt.Fprintf(os.Stderr, "-multi mounts an empty root; write \"mount <name> <archive>\"\n")
+	fmt.Fprintf(os.Stderr, "or \"umount <name>\" to its .ctl file to add or remove archives\n")
 	flag.PrintDefaults()
 }
 
 // FS filesystem
 type FS struct {
-	archive *zip.Reader
+	archive ArchiveFS
+	root    *node
 }
 
 var _ fs.FS = (*FS)(nil)
 
 func (f *FS) Root() (fs.Node, error) {
 	n := &Dir{
-		archive: f.archive,
+		fs:   f,
+		node: f.root,
 	}
 	return n, nil
 }
 
 type Dir struct {
-	archive *zip.Reader
-	// nil for the root directory, which has no entry in the zip
-	file *zip.File
+	fs   *FS
+	node *node
 }
 
 var _ fs.Node = (*Dir)(nil)
 
+// Attr doesn't set Crtime: this bazil.org/fuse version's fuse.Attr has no
+// such field (only Atime/Mtime/Ctime), and archive formats generally don't
+// record a creation time distinct from ModTime anyway.
 func (d *Dir) Attr(ctx context.Context, attr *fuse.Attr) error {
-	if d.file == nil {
-		// root directory
+	if d.node.entry == nil {
+		// root, or a directory synthesized from a deeper entry's path
 		attr.Mode = os.ModeDir | 0755
 	} else {
-		attr.Mode = d.file.Mode()
-		attr.Mtime = d.file.ModTime()
-		attr.Ctime = d.file.ModTime()
-		attr.Crtime = d.file.ModTime()
-		attr.Size = d.file.UncompressedSize64
+		attr.Mode = d.node.entry.Mode
+		attr.Mtime = d.node.entry.ModTime
+		attr.Ctime = d.node.entry.ModTime
+		attr.Size = uint64(d.node.entry.Size)
 	}
 	return nil
 }
 
 type File struct {
-	file *zip.File
+	fs   *FS
+	node *node
 }
 
 var _ fs.Node = (*File)(nil)
 
+// Attr doesn't set Crtime: see the comment on Dir.Attr.
 func (f *File) Attr(ctx context.Context, attr *fuse.Attr) error {
-	attr.Mode = f.file.Mode()
-	attr.Mtime = f.file.ModTime()
-	attr.Ctime = f.file.ModTime()
-	attr.Crtime = f.file.ModTime()
-	attr.Size = f.file.UncompressedSize64
+	attr.Mode = f.node.entry.Mode
+	attr.Mtime = f.node.entry.ModTime
+	attr.Ctime = f.node.entry.ModTime
+	attr.Size = uint64(f.node.entry.Size)
 	return nil
 }
 
 var _ = fs.NodeRequestLookuper(&Dir{})
 
 func (d *Dir) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.LookupResponse) (fs.Node, error) {
-	path := req.Name
-	if d.file != nil {
-		path = d.file.Name + path
+	child, ok := d.node.children[req.Name]
+	if !ok {
+		return nil, fuse.ENOENT
 	}
-	for _, f := range d.archive.File {
-		switch {
-		case f.Name == path:
-			child := &File{
-				file: f,
-			}
-			return child, nil
-		case f.Name[:len(f.Name)-1] == path && f.Name[len(f.Name)-1] == '/':
-			child := &Dir{
-				archive: d.archive,
-				file:    f,
-			}
-			return child, nil
-		}
+	switch {
+	case child.isSymlink():
+		return &Symlink{fs: d.fs, node: child}, nil
+	case child.isDir():
+		return &Dir{fs: d.fs, node: child}, nil
+	default:
+		return &File{fs: d.fs, node: child}, nil
 	}
-	return nil, fuse.ENOENT
 }
 
 var _ = fs.NodeOpener(&File{})
 
 func (f *File) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
-	r, err := f.file.Open()
+	h, err := openEntryHandle(f.fs.archive, f.node.entry.Name)
 	if err != nil {
 		return nil, err
 	}
-	// individual entries inside a zip file are not seekable
-	resp.Flags |= fuse.OpenNonSeekable
-	return &FileHandle{r: r}, nil
+	return &FileHandle{h: h}, nil
 }
 
 type FileHandle struct {
-	r io.ReadCloser
+	h EntryHandle
 }
 
 var _ fs.Handle = (*FileHandle)(nil)
@@ -120,18 +117,17 @@ var _ fs.Handle = (*FileHandle)(nil)
 var _ fs.HandleReleaser = (*FileHandle)(nil)
 
 func (fh *FileHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
-	return fh.r.Close()
+	return fh.h.Close()
 }
 
 var _ = fs.HandleReader(&FileHandle{})
 
 func (fh *FileHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
-	// We don't actually enforce Offset to match where previous read
-	// ended. Maybe we should, but that would mean'd we need to track
-	// it. The kernel *should* do it for us, based on the
-	// fuse.OpenNonSeekable flag.
 	buf := make([]byte, req.Size)
-	n, err := fh.r.Read(buf)
+	n, err := fh.h.ReadAt(buf, req.Offset)
+	if err == io.EOF {
+		err = nil
+	}
 	resp.Data = buf[:n]
 	return err
 }
@@ -139,44 +135,36 @@ func (fh *FileHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fus
 var _ = fs.HandleReadDirAller(&Dir{})
 
 func (d *Dir) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
-	prefix := ""
-	if d.file != nil {
-		prefix = d.file.Name
-	}
-
-	var res []fuse.Dirent
-	for _, f := range d.archive.File {
-		if !strings.HasPrefix(f.Name, prefix) {
-			continue
-		}
-		name := f.Name[len(prefix):]
-		if name == "" {
-			// the dir itself, not a child
-			continue
-		}
-		if strings.ContainsRune(name[:len(name)-1], '/') {
-			// contains slash in the middle -> is in a deeper subdir
-			continue
-		}
-		var de fuse.Dirent
-		if name[len(name)-1] == '/' {
-			// directory
-			name = name[:len(name)-1]
+	res := make([]fuse.Dirent, 0, len(d.node.children))
+	for name, child := range d.node.children {
+		de := fuse.Dirent{Name: name}
+		switch {
+		case child.isSymlink():
+			de.Type = fuse.DT_Link
+		case child.isDir():
 			de.Type = fuse.DT_Dir
 		}
-		de.Name = name
 		res = append(res, de)
 	}
 	return res, nil
 }
 
-func mount(zipFile, mnt string) error {
-	archive, err := zip.OpenReader(zipFile)
+func mount(archivePath, mnt string, cacheSize int, insecure bool) error {
+	archive, err := OpenArchive(archivePath, cacheSize)
 	if err != nil {
 		return err
 	}
 	defer archive.Close()
 
+	entries, err := filterEntries(archive, archive.Entries(), insecure)
+	if err != nil {
+		return err
+	}
+
+	// fuse.Mount in this package version has no Conn.Ready/Conn.MountError
+	// to wait on for an async mount failure (some older bazil.org/fuse
+	// releases had that pattern); Mount's own error return is the only
+	// signal and already covers mount failures synchronously.
 	con, err := fuse.Mount(mnt)
 	if err != nil {
 		return err
@@ -184,31 +172,63 @@ func mount(zipFile, mnt string) error {
 	defer con.Close()
 
 	filesys := &FS{
-		archive: &archive.Reader,
+		archive: archive,
+		root:    buildTree(entries),
 	}
 
-	if err := fs.Serve(con, filesys); err != nil {
+	return fs.Serve(con, filesys)
+}
+
+// mountMulti mounts a synthetic root at mnt that starts out empty and
+// hosts archives mounted and unmounted at runtime via its .ctl control
+// file (see multizip.go).
+func mountMulti(mnt string, cacheSize int, insecure bool) error {
+	// See the comment in mount: this fuse package version has no
+	// Conn.Ready/Conn.MountError to wait on, so there's nothing more to
+	// check here beyond Mount's synchronous error return.
+	con, err := fuse.Mount(mnt)
+	if err != nil {
 		return err
 	}
+	defer con.Close()
 
-	<-con.Ready
-	return con.MountError
+	filesys := &multiFS{root: &multiRoot{
+		cacheSize: cacheSize,
+		insecure:  insecure,
+		mounted:   make(map[string]*mountedArchive),
+	}}
+
+	return fs.Serve(con, filesys)
 }
 
 func main() {
 	log.SetFlags(0)
 	log.SetPrefix(progName + ": ")
 
+	cacheSize := flag.Int("cache-size", defaultCacheSize, "block cache size in bytes, for http(s) archive URLs")
+	multi := flag.Bool("multi", false, "mount an empty root hosting archives added/removed at runtime via its .ctl file")
+	insecure := flag.Bool("insecure", false, "skip entries with insecure names (absolute paths, .. components, backslashes) instead of aborting the mount")
 	flag.Usage = usage
 	flag.Parse()
 
+	if *multi {
+		if flag.NArg() != 1 {
+			usage()
+			os.Exit(2)
+		}
+		if err := mountMulti(flag.Arg(0), *cacheSize, *insecure); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if flag.NArg() != 2 {
 		usage()
 		os.Exit(2)
 	}
-	zipFile := flag.Arg(0)
+	archivePath := flag.Arg(0)
 	mntpoint := flag.Arg(1)
-	if err := mount(zipFile, mntpoint); err != nil {
+	if err := mount(archivePath, mntpoint, *cacheSize, *insecure); err != nil {
 		log.Fatal(err)
 	}
 }
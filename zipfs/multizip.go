@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+)
+
+// ctlName is the magic control file used to mount and unmount archives in
+// a multizip root.
+const ctlName = ".ctl"
+
+// multiFS is the top-level filesystem for a multizip mount: a synthetic
+// root directory holding one child directory per mounted archive plus the
+// .ctl control file, rather than a single archive's tree.
+type multiFS struct {
+	root *multiRoot
+}
+
+var _ fs.FS = (*multiFS)(nil)
+
+func (f *multiFS) Root() (fs.Node, error) {
+	return f.root, nil
+}
+
+// mountedArchive is one archive currently mounted under a multizip root.
+type mountedArchive struct {
+	archive ArchiveFS
+	tree    *node
+}
+
+// multiRoot is the root directory of a multizip mount. Unlike Dir, its set
+// of children changes at runtime as archives are mounted and unmounted
+// through the .ctl file.
+type multiRoot struct {
+	cacheSize int
+	insecure  bool
+
+	mu      sync.Mutex
+	mounted map[string]*mountedArchive
+}
+
+var _ fs.Node = (*multiRoot)(nil)
+
+func (r *multiRoot) Attr(ctx context.Context, attr *fuse.Attr) error {
+	attr.Mode = os.ModeDir | 0755
+	return nil
+}
+
+var _ = fs.NodeRequestLookuper(&multiRoot{})
+
+func (r *multiRoot) Lookup(ctx context.Context, req *fuse.LookupRequest, resp *fuse.LookupResponse) (fs.Node, error) {
+	if req.Name == ctlName {
+		return &ctlFile{root: r}, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.mounted[req.Name]
+	if !ok {
+		return nil, fuse.ENOENT
+	}
+	return &Dir{fs: &FS{archive: m.archive, root: m.tree}, node: m.tree}, nil
+}
+
+var _ = fs.HandleReadDirAller(&multiRoot{})
+
+func (r *multiRoot) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	res := make([]fuse.Dirent, 0, len(r.mounted)+1)
+	res = append(res, fuse.Dirent{Name: ctlName, Type: fuse.DT_File})
+	for name := range r.mounted {
+		res = append(res, fuse.Dirent{Name: name, Type: fuse.DT_Dir})
+	}
+	return res, nil
+}
+
+// Remove lets `rmdir <name>` at the mountpoint unmount an archive, as an
+// alternative to writing "umount <name>" to .ctl. Regular files can't be
+// removed from the root since the only one that exists, .ctl, isn't
+// managed this way.
+var _ = fs.NodeRemover(&multiRoot{})
+
+func (r *multiRoot) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
+	if !req.Dir {
+		return fuse.EPERM
+	}
+	return r.umount(req.Name)
+}
+
+// Create always fails: entries under the root are only ever created by
+// writing a "mount" command to .ctl, never by regular file creation.
+var _ = fs.NodeCreater(&multiRoot{})
+
+func (r *multiRoot) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (fs.Node, fs.Handle, error) {
+	return nil, nil, fuse.EPERM
+}
+
+func (r *multiRoot) mount(name, archivePath string) error {
+	if name == "" || name == ctlName || strings.ContainsRune(name, '/') {
+		return fmt.Errorf("mount: invalid name %q", name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.mounted[name]; exists {
+		return fmt.Errorf("mount: %q is already mounted", name)
+	}
+
+	archive, err := OpenArchive(archivePath, r.cacheSize)
+	if err != nil {
+		return err
+	}
+	entries, err := filterEntries(archive, archive.Entries(), r.insecure)
+	if err != nil {
+		archive.Close()
+		return err
+	}
+	r.mounted[name] = &mountedArchive{
+		archive: archive,
+		tree:    buildTree(entries),
+	}
+	return nil
+}
+
+func (r *multiRoot) umount(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	m, ok := r.mounted[name]
+	if !ok {
+		return fuse.ENOENT
+	}
+	delete(r.mounted, name)
+	return m.archive.Close()
+}
+
+// ctlFile is the .ctl control file: writing "mount <name> <path-or-url>"
+// mounts an archive as /<name>, and "umount <name>" removes it.
+type ctlFile struct {
+	root *multiRoot
+}
+
+var _ fs.Node = (*ctlFile)(nil)
+
+func (c *ctlFile) Attr(ctx context.Context, attr *fuse.Attr) error {
+	attr.Mode = 0644
+	return nil
+}
+
+var _ = fs.NodeOpener(&ctlFile{})
+
+func (c *ctlFile) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (fs.Handle, error) {
+	return c, nil
+}
+
+var _ = fs.HandleWriter(&ctlFile{})
+
+func (c *ctlFile) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
+	for _, line := range strings.Split(string(req.Data), "\n") {
+		if err := c.runCommand(line); err != nil {
+			return err
+		}
+	}
+	resp.Size = len(req.Data)
+	return nil
+}
+
+func (c *ctlFile) runCommand(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	switch fields[0] {
+	case "mount":
+		if len(fields) != 3 {
+			return fmt.Errorf("ctl: usage: mount <name> <path-or-url>")
+		}
+		return c.root.mount(fields[1], fields[2])
+	case "umount":
+		if len(fields) != 2 {
+			return fmt.Errorf("ctl: usage: umount <name>")
+		}
+		return c.root.umount(fields[1])
+	default:
+		return fmt.Errorf("ctl: unknown command %q", fields[0])
+	}
+}
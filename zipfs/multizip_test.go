@@ -0,0 +1,76 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestRoot() *multiRoot {
+	return &multiRoot{mounted: make(map[string]*mountedArchive)}
+}
+
+func TestMultiRootMountAndUmount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.zip")
+	writeZipFixture(t, path)
+
+	r := newTestRoot()
+	if err := r.mount("a", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.mounted["a"]; !ok {
+		t.Fatal("expected \"a\" to be mounted")
+	}
+
+	if err := r.mount("a", path); err == nil {
+		t.Fatal("expected error mounting an already-mounted name")
+	}
+
+	if err := r.umount("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.mounted["a"]; ok {
+		t.Fatal("expected \"a\" to be unmounted")
+	}
+
+	if err := r.umount("a"); err == nil {
+		t.Fatal("expected error unmounting a name that isn't mounted")
+	}
+}
+
+func TestMultiRootMountRejectsBadNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.zip")
+	writeZipFixture(t, path)
+
+	r := newTestRoot()
+	for _, name := range []string{"", ctlName, "a/b"} {
+		if err := r.mount(name, path); err == nil {
+			t.Errorf("mount(%q): expected error", name)
+		}
+	}
+}
+
+func TestCtlFileRunsCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.zip")
+	writeZipFixture(t, path)
+
+	r := newTestRoot()
+	c := &ctlFile{root: r}
+
+	if err := c.runCommand("mount a " + path); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.mounted["a"]; !ok {
+		t.Fatal("expected \"a\" to be mounted after ctl command")
+	}
+
+	if err := c.runCommand("umount a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.mounted["a"]; ok {
+		t.Fatal("expected \"a\" to be unmounted after ctl command")
+	}
+
+	if err := c.runCommand("bogus"); err == nil {
+		t.Fatal("expected error for unknown ctl command")
+	}
+}
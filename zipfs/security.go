@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isSecureEntryName reports whether name is safe to extract relative to a
+// mountpoint: no absolute paths, no ".." components, and (on Unix, where
+// backslash is an ordinary filename character rather than a separator) no
+// backslashes, which some tools on other platforms would treat as path
+// separators. This is the same ErrInsecurePath discipline archive/zip
+// itself adopted for Reader.Open. The same rule applies to a symlink's
+// target: a relative ".." or an absolute path can walk a reader straight
+// out of the mountpoint when followed.
+func isSecureEntryName(name string) bool {
+	name = strings.TrimSuffix(name, "/")
+	if name == "" {
+		return true
+	}
+	if strings.ContainsRune(name, '\\') {
+		return false
+	}
+	return filepath.IsLocal(name)
+}
+
+// filterEntries checks every entry's name, and every symlink's target,
+// against isSecureEntryName. In strict mode (the default) a single
+// insecure entry aborts the whole mount with a clear error; with insecure
+// set, insecure entries are skipped instead so the rest of the archive
+// still mounts.
+func filterEntries(archive ArchiveFS, entries []ArchiveEntry, insecure bool) ([]ArchiveEntry, error) {
+	kept := make([]ArchiveEntry, 0, len(entries))
+	for _, e := range entries {
+		if !isSecureEntryName(e.Name) {
+			if !insecure {
+				return nil, fmt.Errorf("insecure entry name %q (pass -insecure to skip such entries instead of aborting)", e.Name)
+			}
+			continue
+		}
+
+		if e.Mode&os.ModeSymlink != 0 {
+			target, err := symlinkTarget(archive, e)
+			if err != nil {
+				return nil, err
+			}
+			if !isSecureEntryName(target) {
+				if !insecure {
+					return nil, fmt.Errorf("insecure symlink %q -> %q (pass -insecure to skip such entries instead of aborting)", e.Name, target)
+				}
+				continue
+			}
+		}
+
+		kept = append(kept, e)
+	}
+	return kept, nil
+}
+
+// symlinkTarget returns a symlink entry's target, using the format's
+// out-of-band storage (tar) when available and otherwise reading it from
+// the entry's body (zip's convention).
+func symlinkTarget(archive ArchiveFS, e ArchiveEntry) (string, error) {
+	if e.LinkTarget != "" {
+		return e.LinkTarget, nil
+	}
+	r, err := archive.Open(e.Name)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIsSecureEntryName(t *testing.T) {
+	cases := []struct {
+		name string
+		want bool
+	}{
+		{"a/b.txt", true},
+		{"dir/", true},
+		{"", true},
+		{"/etc/passwd", false},
+		{"../escape.txt", false},
+		{"a/../../escape.txt", false},
+		{`a\..\escape.txt`, false},
+	}
+	for _, c := range cases {
+		if got := isSecureEntryName(c.name); got != c.want {
+			t.Errorf("isSecureEntryName(%q) = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFilterEntriesStrictAborts(t *testing.T) {
+	entries := []ArchiveEntry{{Name: "ok.txt"}, {Name: "../escape.txt"}}
+	if _, err := filterEntries(nil, entries, false); err == nil {
+		t.Fatal("expected strict mode to abort on an insecure entry")
+	}
+}
+
+func TestFilterEntriesInsecureSkips(t *testing.T) {
+	entries := []ArchiveEntry{{Name: "ok.txt"}, {Name: "../escape.txt"}}
+	kept, err := filterEntries(nil, entries, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 || kept[0].Name != "ok.txt" {
+		t.Fatalf("kept = %+v, want only ok.txt", kept)
+	}
+}
+
+func TestFilterEntriesRejectsInsecureSymlinkTarget(t *testing.T) {
+	entries := []ArchiveEntry{
+		{Name: "good-link", Mode: os.ModeSymlink | 0777, LinkTarget: "sibling.txt"},
+		{Name: "bad-link", Mode: os.ModeSymlink | 0777, LinkTarget: "../../etc/passwd"},
+	}
+
+	if _, err := filterEntries(nil, entries, false); err == nil {
+		t.Fatal("expected strict mode to abort on an insecure symlink target")
+	}
+
+	kept, err := filterEntries(nil, entries, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 || kept[0].Name != "good-link" {
+		t.Fatalf("kept = %+v, want only good-link", kept)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"bazil.org/fuse"
+	"bazil.org/fuse/fs"
+)
+
+// Symlink is a zip entry whose mode has the symlink bit set. Its contents
+// are the link target, exactly as with a real symlink's target stored out
+// of band; archive/zip and tar both store it as the entry's body.
+type Symlink struct {
+	fs   *FS
+	node *node
+}
+
+var _ fs.Node = (*Symlink)(nil)
+
+func (s *Symlink) Attr(ctx context.Context, attr *fuse.Attr) error {
+	attr.Mode = s.node.entry.Mode
+	attr.Mtime = s.node.entry.ModTime
+	attr.Ctime = s.node.entry.ModTime
+	attr.Size = uint64(s.node.entry.Size)
+	return nil
+}
+
+var _ = fs.NodeReadlinker(&Symlink{})
+
+func (s *Symlink) Readlink(ctx context.Context, req *fuse.ReadlinkRequest) (string, error) {
+	if s.node.entry.LinkTarget != "" {
+		return s.node.entry.LinkTarget, nil
+	}
+
+	r, err := s.fs.archive.Open(s.node.entry.Name)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	target, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(target), nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// node is one entry in the directory tree built once at mount time from
+// an ArchiveFS's entry list. entry is nil for directories synthesized
+// because some deeper entry implies them but they have no explicit
+// directory entry of their own in the archive (a common zip quirk) — the
+// root is always such a node.
+type node struct {
+	entry    *ArchiveEntry
+	children map[string]*node
+}
+
+func newNode() *node {
+	return &node{children: make(map[string]*node)}
+}
+
+// isDir reports whether n should be presented as a directory: it has
+// children, an explicit entry marked as a directory, or (the root) no
+// entry at all.
+func (n *node) isDir() bool {
+	if len(n.children) > 0 {
+		return true
+	}
+	if n.entry == nil {
+		return true
+	}
+	return n.entry.Mode.IsDir()
+}
+
+// isSymlink reports whether n has an explicit entry marked as a symlink.
+func (n *node) isSymlink() bool {
+	return n.entry != nil && n.entry.Mode&os.ModeSymlink != 0
+}
+
+// buildTree walks an archive's flat entry list and turns it into a tree,
+// splitting each entry's name on "/" and creating intermediate nodes as
+// needed. This lets Lookup resolve a path component with a single map
+// access instead of scanning every entry, and makes ReadDirAll an O(children)
+// iteration instead of an O(entries) scan.
+func buildTree(entries []ArchiveEntry) *node {
+	root := newNode()
+	for i := range entries {
+		e := entries[i]
+		name := strings.TrimSuffix(e.Name, "/")
+		if name == "" {
+			continue
+		}
+		parts := strings.Split(name, "/")
+
+		cur := root
+		for i, part := range parts {
+			child, ok := cur.children[part]
+			if !ok {
+				child = newNode()
+				cur.children[part] = child
+			}
+			if i == len(parts)-1 {
+				child.entry = &e
+			}
+			cur = child
+		}
+	}
+	return root
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestBuildTreeSynthesizesImplicitDirs(t *testing.T) {
+	entries := []ArchiveEntry{
+		{Name: "a/b/c.txt"},
+	}
+	root := buildTree(entries)
+
+	a, ok := root.children["a"]
+	if !ok || !a.isDir() {
+		t.Fatalf("expected synthesized directory %q", "a")
+	}
+	if a.entry != nil {
+		t.Errorf("synthesized dir %q should have no entry", "a")
+	}
+
+	b, ok := a.children["b"]
+	if !ok || !b.isDir() {
+		t.Fatalf("expected synthesized directory %q", "a/b")
+	}
+
+	c, ok := b.children["c.txt"]
+	if !ok {
+		t.Fatalf("expected file %q", "a/b/c.txt")
+	}
+	if c.isDir() {
+		t.Errorf("c.txt should not be a directory")
+	}
+	if c.entry == nil || c.entry.Name != "a/b/c.txt" {
+		t.Errorf("c.txt entry = %+v, want Name a/b/c.txt", c.entry)
+	}
+}
+
+func TestBuildTreeExplicitDirEntry(t *testing.T) {
+	entries := []ArchiveEntry{
+		{Name: "dir/"},
+		{Name: "dir/file.txt"},
+	}
+	root := buildTree(entries)
+
+	dir, ok := root.children["dir"]
+	if !ok || !dir.isDir() {
+		t.Fatalf("expected directory %q", "dir")
+	}
+	if dir.entry == nil || dir.entry.Name != "dir/" {
+		t.Errorf("dir entry = %+v, want Name dir/", dir.entry)
+	}
+	if len(dir.children) != 1 {
+		t.Fatalf("got %d children, want 1", len(dir.children))
+	}
+}
+
+func TestBuildTreeSymlinkEntry(t *testing.T) {
+	entries := []ArchiveEntry{
+		{Name: "link", Mode: os.ModeSymlink | 0777},
+	}
+	root := buildTree(entries)
+
+	link, ok := root.children["link"]
+	if !ok {
+		t.Fatal("expected entry \"link\"")
+	}
+	if !link.isSymlink() {
+		t.Errorf("expected \"link\" to be a symlink")
+	}
+	if link.isDir() {
+		t.Errorf("a symlink should not be reported as a directory")
+	}
+}